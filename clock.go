@@ -0,0 +1,50 @@
+package cticker
+
+import "time"
+
+// TickSource is the interface implemented by the underlying ticker that
+// feeds a Ticker's tick loop. It abstracts *time.Ticker so that it can be
+// replaced in tests, see Clock.
+type TickSource interface {
+	Ch() <-chan time.Time
+	Stop()
+}
+
+// runtimeTicker wraps time.Ticker implementing TickSource.
+type runtimeTicker struct {
+	*time.Ticker
+}
+
+// Ch implements TickSource.
+func (t *runtimeTicker) Ch() <-chan time.Time {
+	return t.C
+}
+
+// Clock abstracts the time source used by a Ticker, so that tests can
+// control virtual time instead of racing against the wall clock. New and
+// NewWithOptions use RealClock; tests can supply a deterministic Clock,
+// such as the one in cticker/ctickertest, via NewWithClock.
+type Clock interface {
+	// Now returns the current time, see time.Now.
+	Now() time.Time
+	// NewTicker returns a TickSource which ticks every d, see
+	// time.NewTicker.
+	NewTicker(d time.Duration) TickSource
+	// Sleep pauses the current goroutine for at least d, see time.Sleep.
+	Sleep(d time.Duration)
+}
+
+// RealClock is a Clock backed by the wall clock and runtime timers. It is
+// the Clock used by New and NewWithOptions.
+type RealClock struct{}
+
+// Now implements Clock.
+func (RealClock) Now() time.Time { return time.Now() }
+
+// NewTicker implements Clock.
+func (RealClock) NewTicker(d time.Duration) TickSource {
+	return &runtimeTicker{Ticker: time.NewTicker(d)}
+}
+
+// Sleep implements Clock.
+func (RealClock) Sleep(d time.Duration) { time.Sleep(d) }
@@ -0,0 +1,222 @@
+// Package cron parses cron-style schedule specs and drives a Scheduler
+// which delivers ticks on wall clock boundaries, for schedules too
+// irregular to express as a single fixed period, e.g. "every weekday at
+// 09:00".
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule describes a recurring point in time. Next returns the next
+// activation strictly after t.
+type Schedule interface {
+	Next(t time.Time) time.Time
+}
+
+// Parse parses a standard 5 field cron spec ("minute hour dom month
+// dow"), or one of the macros "@yearly", "@annually", "@monthly",
+// "@weekly", "@daily", "@midnight", "@hourly" or "@every <duration>".
+//
+// Each of the 5 fields accepts "*", a number, a range ("a-b"), a step
+// ("*/n" or "a-b/n") or a comma separated list of any of those. Day of
+// month and day of week are combined with a logical AND rather than the
+// OR classic cron uses when both are restricted; this only matters for
+// specs that restrict both fields at once.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@") {
+		return parseMacro(spec)
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron: expected 5 fields, got %d: %q", len(fields), spec)
+	}
+
+	minute, err := parseField(fields[0], 0, 59)
+	if err != nil {
+		return nil, err
+	}
+	hour, err := parseField(fields[1], 0, 23)
+	if err != nil {
+		return nil, err
+	}
+	dom, err := parseField(fields[2], 1, 31)
+	if err != nil {
+		return nil, err
+	}
+	month, err := parseField(fields[3], 1, 12)
+	if err != nil {
+		return nil, err
+	}
+	dow, err := parseField(fields[4], 0, 7)
+	if err != nil {
+		return nil, err
+	}
+	if dow&(1<<7) != 0 {
+		dow |= 1 << 0 // 7 is Sunday too.
+	}
+
+	return &fieldSchedule{minute: minute, hour: hour, dom: dom, month: month, dow: dow}, nil
+}
+
+// parseMacro parses the "@..." schedule shorthands.
+func parseMacro(spec string) (Schedule, error) {
+	switch spec {
+	case "@yearly", "@annually":
+		return Parse("0 0 1 1 *")
+	case "@monthly":
+		return Parse("0 0 1 * *")
+	case "@weekly":
+		return Parse("0 0 * * 0")
+	case "@daily", "@midnight":
+		return Parse("0 0 * * *")
+	case "@hourly":
+		return Parse("0 * * * *")
+	}
+
+	if rest, ok := strings.CutPrefix(spec, "@every "); ok {
+		d, err := time.ParseDuration(rest)
+		if err != nil {
+			return nil, fmt.Errorf("cron: invalid @every duration %q: %w", spec, err)
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("cron: @every duration must be positive: %q", spec)
+		}
+		return constantDelaySchedule(d), nil
+	}
+
+	return nil, fmt.Errorf("cron: unrecognised schedule: %q", spec)
+}
+
+// parseField parses a single comma separated cron field into a bitmask
+// of the values in [min, max] that it matches.
+func parseField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		partBits, err := parseRange(part, min, max)
+		if err != nil {
+			return 0, err
+		}
+		bits |= partBits
+	}
+	return bits, nil
+}
+
+// parseRange parses a single "*", "n", "a-b", "*/n" or "a-b/n" term into
+// a bitmask of the values in [min, max] that it matches.
+func parseRange(expr string, min, max int) (uint64, error) {
+	rangeAndStep := strings.SplitN(expr, "/", 2)
+	lowAndHigh := strings.SplitN(rangeAndStep[0], "-", 2)
+
+	var start, end int
+	var err error
+	if lowAndHigh[0] == "*" {
+		start, end = min, max
+	} else {
+		if start, err = strconv.Atoi(lowAndHigh[0]); err != nil {
+			return 0, fmt.Errorf("cron: invalid value %q: %w", expr, err)
+		}
+		end = start
+		if len(lowAndHigh) == 2 {
+			if end, err = strconv.Atoi(lowAndHigh[1]); err != nil {
+				return 0, fmt.Errorf("cron: invalid value %q: %w", expr, err)
+			}
+		}
+	}
+	if start < min || end > max || start > end {
+		return 0, fmt.Errorf("cron: value out of range [%d, %d]: %q", min, max, expr)
+	}
+
+	step := 1
+	if len(rangeAndStep) == 2 {
+		if step, err = strconv.Atoi(rangeAndStep[1]); err != nil {
+			return 0, fmt.Errorf("cron: invalid step %q: %w", expr, err)
+		}
+	}
+	if step <= 0 {
+		return 0, fmt.Errorf("cron: step must be positive: %q", expr)
+	}
+
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << uint(i)
+	}
+	return bits, nil
+}
+
+// fieldSchedule is a Schedule built from a standard 5 field cron spec,
+// represented as a bitmask per field.
+type fieldSchedule struct {
+	minute, hour, dom, month, dow uint64
+}
+
+// yearsAhead bounds how far Next will search before giving up, to avoid
+// looping forever on a spec that can never match, e.g. "0 0 30 2 *".
+const yearsAhead = 5
+
+// Next implements Schedule.
+func (f *fieldSchedule) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Second).Add(time.Minute).Truncate(time.Minute)
+	yearLimit := t.Year() + yearsAhead
+
+wrap:
+	if t.Year() > yearLimit {
+		return time.Time{}
+	}
+
+	for f.month&(1<<uint(t.Month())) == 0 {
+		t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()).AddDate(0, 1, 0)
+		if t.Month() == time.January {
+			goto wrap
+		}
+	}
+
+	for !f.dayMatches(t) {
+		t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()).AddDate(0, 0, 1)
+		if t.Day() == 1 {
+			goto wrap
+		}
+	}
+
+	for f.hour&(1<<uint(t.Hour())) == 0 {
+		// Step via local wall-clock components rather than
+		// t.Truncate(time.Hour): Truncate rounds on absolute time since
+		// the zero time, which only lines up with the local hour
+		// boundary when the zone's UTC offset is a whole number of
+		// hours, e.g. it's wrong for Asia/Kolkata (+5:30).
+		t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		if t.Hour() == 0 {
+			goto wrap
+		}
+	}
+
+	for f.minute&(1<<uint(t.Minute())) == 0 {
+		t = t.Add(time.Minute)
+		if t.Minute() == 0 {
+			goto wrap
+		}
+	}
+
+	return t
+}
+
+// dayMatches reports whether t matches both the day-of-month and
+// day-of-week fields.
+func (f *fieldSchedule) dayMatches(t time.Time) bool {
+	return f.dom&(1<<uint(t.Day())) != 0 && f.dow&(1<<uint(t.Weekday())) != 0
+}
+
+// constantDelaySchedule is the Schedule produced by "@every <duration>":
+// it activates every d after the previous activation, with no wall
+// clock alignment.
+type constantDelaySchedule time.Duration
+
+// Next implements Schedule.
+func (d constantDelaySchedule) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(d))
+}
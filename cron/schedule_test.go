@@ -0,0 +1,73 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/multiplay/go-cticker/cron"
+)
+
+func TestParse_EveryFiveMinutes(t *testing.T) {
+	s, err := cron.Parse("*/5 * * * *")
+	require.NoError(t, err)
+
+	from := time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 5, 0, 0, time.UTC), s.Next(from))
+
+	from = time.Date(2024, 1, 2, 3, 6, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 10, 0, 0, time.UTC), s.Next(from))
+}
+
+func TestParse_Weekday9am(t *testing.T) {
+	s, err := cron.Parse("0 9 * * 1-5")
+	require.NoError(t, err)
+
+	// A Friday at 09:30 should roll over to Monday at 09:00.
+	friday := time.Date(2024, 1, 5, 9, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2024, 1, 8, 9, 0, 0, 0, time.UTC), s.Next(friday))
+}
+
+// TestParse_NonWholeHourOffset checks that the hour field steps by local
+// wall-clock hour, not by truncating absolute time, so it converges in
+// zones whose UTC offset isn't a whole number of hours.
+func TestParse_NonWholeHourOffset(t *testing.T) {
+	s, err := cron.Parse("0 9 * * *")
+	require.NoError(t, err)
+
+	loc := time.FixedZone("IST", (5*60+30)*60)
+	from := time.Date(2024, 1, 2, 9, 30, 0, 0, loc)
+	assert.Equal(t, time.Date(2024, 1, 3, 9, 0, 0, 0, loc), s.Next(from))
+}
+
+func TestParse_Macros(t *testing.T) {
+	for _, spec := range []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"} {
+		_, err := cron.Parse(spec)
+		assert.NoError(t, err, spec)
+	}
+}
+
+func TestParse_Every(t *testing.T) {
+	s, err := cron.Parse("@every 1h30m")
+	require.NoError(t, err)
+
+	from := time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC)
+	assert.Equal(t, from.Add(time.Hour+time.Minute*30), s.Next(from))
+}
+
+func TestParse_Invalid(t *testing.T) {
+	cases := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* * * * 8",
+		"@every notaduration",
+		"@nonsense",
+	}
+	for _, spec := range cases {
+		_, err := cron.Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
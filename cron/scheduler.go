@@ -0,0 +1,150 @@
+package cron
+
+import (
+	"sync"
+	"time"
+
+	"github.com/multiplay/go-cticker"
+)
+
+// Scheduler holds a channel that delivers ticks according to a parsed
+// cron Schedule, for schedules too irregular for a single fixed period
+// to express, e.g. "every weekday at 09:00 local". It reuses the same
+// sync-to-accuracy, poll-and-compare design as cticker.Ticker, so it
+// remains robust to clock drift and adjustments: next is recomputed
+// from the schedule after every fire, and a wall clock jump past a
+// missed occurrence causes an immediate tick rather than a wait for the
+// next poll to happen to land on it.
+type Scheduler struct {
+	mtx      sync.Mutex
+	C        <-chan time.Time // The channel on which ticks are delivered.
+	c        chan time.Time
+	schedule Schedule
+	a        time.Duration
+	clock    cticker.Clock
+
+	next    time.Time
+	done    chan struct{}
+	stopped bool // true once Stop/StopClose has been called.
+	closeC  bool
+	closedC sync.Once
+	ticker  cticker.TickSource
+}
+
+// New returns a new Scheduler that delivers a tick on each activation of
+// spec, polled with the given accuracy. See Parse for the supported
+// spec syntax.
+//
+// Stop the Scheduler to release its associated resources.
+func New(spec string, accuracy time.Duration) (*Scheduler, error) {
+	return NewWithClock(spec, accuracy, cticker.RealClock{})
+}
+
+// NewWithClock is like New, but lets the caller supply the Clock used to
+// read the current time, create the underlying runtime ticker and
+// sleep. It is intended for tests that need deterministic, non-wall-
+// clock time, see cticker/ctickertest.
+func NewWithClock(spec string, accuracy time.Duration, clock cticker.Clock) (*Scheduler, error) {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return newSchedulerWithClock(schedule, accuracy, clock), nil
+}
+
+func newSchedulerWithClock(schedule Schedule, accuracy time.Duration, clock cticker.Clock) *Scheduler {
+	// We hold one element time buffer, if the consumer falls behind
+	// while reading the values, we drop the ticks until it catches
+	// back up.
+	c := make(chan time.Time, 1)
+	now := clock.Now()
+	s := &Scheduler{
+		C:        c,
+		c:        c,
+		schedule: schedule,
+		a:        accuracy,
+		clock:    clock,
+		next:     schedule.Next(now),
+		done:     make(chan struct{}),
+	}
+
+	go s.sync(now)
+
+	return s
+}
+
+// sync sleeps until the next accuracy boundary and then starts the
+// internal poll loop against a freshly created runtime ticker, unless
+// the Scheduler has been stopped in the meantime.
+func (s *Scheduler) sync(now time.Time) {
+	s.clock.Sleep(now.Truncate(s.a).Add(s.a).Sub(now))
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	select {
+	case <-s.done:
+		// Already stopped.
+	default:
+		s.ticker = s.clock.NewTicker(s.a)
+		go s.tick(s.c)
+	}
+}
+
+// Stop turns off the Scheduler. After Stop, no more ticks will be sent.
+// Stop does not close the channel to prevent a read from the channel
+// succeeding incorrectly.
+func (s *Scheduler) Stop() {
+	s.stop(false)
+}
+
+// StopClose turns off the Scheduler and closes C once any tick already
+// in flight has been delivered, so a range over C terminates. Prefer
+// Stop unless the caller can guarantee nothing else sends on C.
+func (s *Scheduler) StopClose() {
+	s.stop(true)
+}
+
+func (s *Scheduler) stop(closeC bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.stopped {
+		return
+	}
+	s.stopped = true
+	s.closeC = closeC
+	close(s.done)
+	if s.ticker != nil {
+		s.ticker.Stop()
+	} else if closeC {
+		// The sync goroutine hasn't started tick yet, so it never
+		// observed closeC; close here instead.
+		s.closedC.Do(func() { close(s.c) })
+	}
+}
+
+// tick sends ticks to s.C whenever a poll observes that the schedule's
+// next activation has arrived or passed.
+func (s *Scheduler) tick(c chan time.Time) {
+	ticks := s.ticker.Ch()
+	for {
+		select {
+		case now := <-ticks:
+			now = now.Truncate(s.a)
+
+			if now.Compare(s.next) >= 0 {
+				s.next = s.schedule.Next(s.next)
+				select {
+				case c <- now:
+				default:
+					// Consumer is running slowly
+				}
+			}
+		case <-s.done:
+			if s.closeC {
+				s.closedC.Do(func() { close(c) })
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package cron_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/multiplay/go-cticker/cron"
+	"github.com/multiplay/go-cticker/ctickertest"
+)
+
+func TestScheduler_EveryMinute(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC)
+	clock := ctickertest.NewFakeClock(start)
+
+	s, err := cron.NewWithClock("* * * * *", time.Second, clock)
+	require.NoError(t, err)
+	defer s.Stop()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second) // start is accuracy-aligned, so this satisfies the sync sleep.
+	clock.BlockUntil(1)
+
+	for i := 0; i < 60; i++ {
+		clock.Advance(time.Second)
+	}
+
+	select {
+	case tick := <-s.C:
+		assert.Equal(t, start.Truncate(time.Minute).Add(time.Minute), tick)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for tick")
+	}
+}
+
+func TestScheduler_InvalidSpec(t *testing.T) {
+	_, err := cron.New("not a spec", time.Second)
+	assert.Error(t, err)
+}
+
+// TestScheduler_StopClose checks that StopClose delivers exactly the
+// ticks produced before it's called and then closes C, terminating a
+// range over it. It drives the scheduler with a FakeClock rather than a
+// real sleep so the tick count is deterministic instead of racing
+// against wall-clock timing.
+func TestScheduler_StopClose(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC)
+	clock := ctickertest.NewFakeClock(start)
+
+	s, err := cron.NewWithClock("@every 1ms", time.Millisecond, clock)
+	require.NoError(t, err)
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Millisecond) // start is accuracy-aligned, so this satisfies the sync sleep.
+	clock.BlockUntil(1)
+
+	const want = 3
+	ticks := 0
+	for i := 0; i < want; i++ {
+		clock.Advance(time.Millisecond)
+		select {
+		case <-s.C:
+			ticks++
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for tick")
+		}
+	}
+	s.StopClose()
+
+	for range s.C {
+		ticks++
+	}
+	assert.Equal(t, want, ticks)
+}
@@ -0,0 +1,138 @@
+// Package ctickertest provides a fake cticker.Clock implementation so
+// that tests can exercise cticker.Ticker deterministically, by advancing
+// a virtual clock instead of racing against wall-clock timers.
+package ctickertest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/multiplay/go-cticker"
+)
+
+// FakeClock is a cticker.Clock whose Now, Sleep and ticker channels only
+// advance when Advance is called, making tests built on it deterministic.
+// Every registered, unstopped ticker fires exactly once per Advance call, with the
+// clock's new current time, regardless of how it was constructed; this
+// lets a test simulate an arbitrary underlying sample, including one
+// that reflects a large wall clock jump.
+type FakeClock struct {
+	mtx     sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	sleeps  []*sleeper
+	tickers []*fakeTicker
+}
+
+// sleeper tracks a pending FakeClock.Sleep call.
+type sleeper struct {
+	until time.Time
+	done  chan struct{}
+}
+
+// fakeTicker is the cticker.TickSource returned by FakeClock.NewTicker.
+type fakeTicker struct {
+	mtx     sync.Mutex
+	c       chan time.Time
+	stopped bool
+}
+
+// Ch implements cticker.TickSource.
+func (t *fakeTicker) Ch() <-chan time.Time {
+	return t.c
+}
+
+// Stop implements cticker.TickSource.
+func (t *fakeTicker) Stop() {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	t.stopped = true
+}
+
+// NewFakeClock returns a FakeClock whose virtual time starts at start.
+func NewFakeClock(start time.Time) *FakeClock {
+	c := &FakeClock{now: start}
+	c.cond = sync.NewCond(&c.mtx)
+	return c
+}
+
+// Now implements cticker.Clock.
+func (c *FakeClock) Now() time.Time {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.now
+}
+
+// Sleep implements cticker.Clock. It blocks the calling goroutine until
+// a call to Advance moves the virtual clock to or past now+d.
+func (c *FakeClock) Sleep(d time.Duration) {
+	c.mtx.Lock()
+	s := &sleeper{until: c.now.Add(d), done: make(chan struct{})}
+	if !s.until.After(c.now) {
+		c.mtx.Unlock()
+		return
+	}
+	c.sleeps = append(c.sleeps, s)
+	c.cond.Broadcast()
+	c.mtx.Unlock()
+
+	<-s.done
+}
+
+// NewTicker implements cticker.Clock. d is accepted for interface
+// compatibility; the returned TickSource only ticks when Advance is
+// called, once per call, with the clock's new current time.
+func (c *FakeClock) NewTicker(d time.Duration) cticker.TickSource {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	t := &fakeTicker{c: make(chan time.Time, 1)}
+	c.tickers = append(c.tickers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// BlockUntil blocks until n goroutines are waiting on the clock, either
+// asleep in Sleep or having created a ticker via NewTicker. Use it to
+// synchronise a test with a producer goroutine before calling Advance,
+// so that advancing the clock isn't lost on a producer that hasn't
+// registered its wait yet.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	for len(c.sleeps)+len(c.tickers) < n {
+		c.cond.Wait()
+	}
+}
+
+// Advance moves the virtual clock forward by d, waking any pending
+// Sleep calls whose deadline has passed and delivering the new current
+// time to every registered, unstopped ticker exactly once. The send to
+// each ticker's channel blocks, so a tick is never lost to a consumer
+// that hasn't caught up yet from a prior Advance.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mtx.Lock()
+	c.now = c.now.Add(d)
+	now := c.now
+
+	remaining := c.sleeps[:0]
+	for _, s := range c.sleeps {
+		if !now.Before(s.until) {
+			close(s.done)
+		} else {
+			remaining = append(remaining, s)
+		}
+	}
+	c.sleeps = remaining
+
+	tickers := append([]*fakeTicker(nil), c.tickers...)
+	c.mtx.Unlock()
+
+	for _, t := range tickers {
+		t.mtx.Lock()
+		if !t.stopped {
+			t.c <- now
+		}
+		t.mtx.Unlock()
+	}
+}
@@ -0,0 +1,71 @@
+package ctickertest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/multiplay/go-cticker/ctickertest"
+)
+
+func TestFakeClock_Now(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := ctickertest.NewFakeClock(start)
+
+	assert.Equal(t, start, clock.Now())
+
+	clock.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Second), clock.Now())
+}
+
+func TestFakeClock_Sleep(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := ctickertest.NewFakeClock(start)
+
+	woken := make(chan time.Time, 1)
+	go func() {
+		clock.Sleep(time.Minute)
+		woken <- clock.Now()
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Second * 59)
+	select {
+	case <-woken:
+		t.Fatal("Sleep returned before its deadline")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case now := <-woken:
+		assert.Equal(t, start.Add(time.Minute), now)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for Sleep to return")
+	}
+}
+
+func TestFakeClock_NewTicker(t *testing.T) {
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	clock := ctickertest.NewFakeClock(start)
+
+	ticker := clock.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	clock.Advance(time.Second)
+	select {
+	case tick := <-ticker.Ch():
+		assert.Equal(t, start.Add(time.Second), tick)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for tick")
+	}
+
+	ticker.Stop()
+	clock.Advance(time.Second)
+	select {
+	case tick := <-ticker.Ch():
+		t.Fatal("unexpected tick after Stop", tick)
+	case <-time.After(time.Millisecond * 50):
+	}
+}
@@ -0,0 +1,58 @@
+package cticker
+
+import (
+	"math/rand"
+	"time"
+)
+
+// options holds the configuration applied by Option functions passed to
+// NewWithOptions.
+type options struct {
+	offset        time.Duration
+	jitter        time.Duration
+	jumpThreshold time.Duration
+}
+
+// Option configures optional behaviour of a Ticker created via
+// NewWithOptions.
+type Option func(*options)
+
+// WithOffset shifts every tick by offset after the wall clock boundary,
+// e.g. ticking at HH:MM:05 instead of exactly on the minute. offset must
+// be less than d.
+func WithOffset(offset time.Duration) Option {
+	return func(o *options) { o.offset = offset }
+}
+
+// WithJitter adds a random delay sampled from [0, jitter) before each
+// tick is delivered, spreading load across a fleet of processes that
+// would otherwise all fire on the same wall clock boundary. jitter must
+// be no greater than d-accuracy.
+func WithJitter(jitter time.Duration) Option {
+	return func(o *options) { o.jitter = jitter }
+}
+
+// WithJumpThreshold sets how far the delta between successive polls of
+// the clock may differ from the accuracy before it is reported on
+// Jumps. It defaults to the accuracy passed to New, NewWithOptions or
+// NewWithClock, so that any jump large enough to be noticeable at the
+// configured accuracy is reported.
+func WithJumpThreshold(threshold time.Duration) Option {
+	return func(o *options) { o.jumpThreshold = threshold }
+}
+
+// NewWithOptions returns a new Ticker like New, with optional aligned
+// offset, jitter and/or jump threshold applied. See WithOffset,
+// WithJitter and WithJumpThreshold.
+func NewWithOptions(d, accuracy time.Duration, opts ...Option) *Ticker {
+	return newTickerWithClock(d, accuracy, RealClock{}, opts...)
+}
+
+// jitterDelay returns a random delay in [0, t.jitter), or zero if no
+// jitter is configured.
+func (t *Ticker) jitterDelay() time.Duration {
+	if t.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(t.jitter)))
+}
@@ -11,43 +11,31 @@ import (
 	"time"
 )
 
-// ticker is an interface that allows us to mock the runtime ticker for
-// testing.
-type ticker interface {
-	Ch() <-chan time.Time
-	Stop()
-}
-
-// runtimeTicker wraps time.Ticker implementing ticker.
-type runtimeTicker struct {
-	*time.Ticker
-}
-
-func newRuntimeTicker(d time.Duration) ticker {
-	return &runtimeTicker{Ticker: time.NewTicker(d)}
-}
-
-// Ch implements ticker.
-func (t *runtimeTicker) Ch() <-chan time.Time {
-	return t.C
-}
-
-// newTicker is the method used to create a new ticker so we can mock it
-// for testing.
-var newTicker = newRuntimeTicker
-
 // Ticker holds a channel that delivers `ticks` on wall clock boundaries.
 // Unlike time.Ticker it will fire early if the clock is adjusted to an
 // earlier time, therefore it can be used for events which need to trigger
 // on wall clock boundaries e.g. every minute on the minute.
 type Ticker struct {
-	mtx    sync.Mutex
-	C      <-chan time.Time // The channel on which ticks are delivered.
-	d      time.Duration
-	a      time.Duration
-	next   time.Time
-	done   chan struct{}
-	ticker ticker
+	mtx   sync.Mutex
+	C     <-chan time.Time     // The channel on which ticks are delivered.
+	Jumps <-chan time.Duration // The channel on which detected clock jumps are delivered. Best effort, see New.
+	c     chan time.Time
+	jumps chan time.Duration
+	d     time.Duration
+	a     time.Duration
+	clock Clock
+
+	offset        time.Duration
+	jitter        time.Duration
+	jumpThreshold time.Duration
+
+	next    time.Time
+	lastNow time.Time
+	done    chan struct{} // closed to retire the current generation's sync/tick goroutines.
+	stopped bool          // true once Stop/StopClose has been called.
+	closeC  bool
+	closedC sync.Once
+	ticker  TickSource
 }
 
 // New returns a new Ticker containing a channel that will send the
@@ -61,78 +49,258 @@ type Ticker struct {
 // the requested time. Instead it will tick at the next available time
 // after the target time, which should be within accuracy.
 //
+// The returned Ticker also exposes Jumps, which reports the delta
+// between successive clock readings whenever it differs from accuracy
+// by more than the jump threshold, see WithJumpThreshold. Like C, it
+// drops jumps to make up for slow receivers.
+//
 // Stop the ticker to release its associated resources.
 func New(d, accuracy time.Duration) *Ticker {
+	return NewWithOptions(d, accuracy)
+}
+
+// NewWithClock is like New, but lets the caller supply the Clock used to
+// read the current time, create the underlying runtime ticker and sleep.
+// It is intended for tests that need deterministic, non-wall-clock time,
+// see cticker/ctickertest.
+func NewWithClock(d, accuracy time.Duration, clock Clock) *Ticker {
+	return newTickerWithClock(d, accuracy, clock)
+}
+
+func newTickerWithClock(d, accuracy time.Duration, clock Clock, opts ...Option) *Ticker {
 	if d <= accuracy {
 		panic(fmt.Errorf("accuracy %v is not less than duration %v", accuracy, d))
 	}
+
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.offset >= d {
+		panic(fmt.Errorf("offset %v is not less than duration %v", o.offset, d))
+	}
+	if o.jitter > d-accuracy {
+		panic(fmt.Errorf("jitter %v is greater than duration %v minus accuracy %v", o.jitter, d, accuracy))
+	}
+
+	jumpThreshold := o.jumpThreshold
+	if jumpThreshold == 0 {
+		jumpThreshold = accuracy
+	}
+
 	// We hold one element time buffer, if the consumer falls behind
 	// while reading the values, we drop the ticks until it catches
 	// back up.
 	c := make(chan time.Time, 1)
-	now := time.Now()
+	jumps := make(chan time.Duration, 1)
+	now := clock.Now()
+	done := make(chan struct{})
 	t := &Ticker{
-		C:    c,
-		d:    d,
-		a:    accuracy,
-		next: now.Truncate(d).Add(d),
-		done: make(chan struct{}),
+		C:             c,
+		Jumps:         jumps,
+		c:             c,
+		jumps:         jumps,
+		d:             d,
+		a:             accuracy,
+		clock:         clock,
+		offset:        o.offset,
+		jitter:        o.jitter,
+		jumpThreshold: jumpThreshold,
+		next:          now.Truncate(d).Add(d).Add(o.offset),
+		lastNow:       now,
+		done:          done,
 	}
 
-	go func() {
-		// Synchronise to the accuracy.
-		time.Sleep(now.Truncate(accuracy).Add(accuracy).Sub(now))
+	go t.sync(now, accuracy, done)
 
-		t.mtx.Lock()
-		defer t.mtx.Unlock()
-		select {
-		case <-t.done:
-			// Already stopped.
-		default:
-			t.ticker = newTicker(accuracy)
-			go t.tick(c)
-		}
+	return t
+}
 
-	}()
+// Reset stops the ticker and starts it again with period d and accuracy,
+// without allocating a new Ticker or a new channel. It mirrors
+// time.Ticker.Reset and is intended for callers that hot-reload their
+// interval configuration: the exported C channel, and any pending
+// selects on it, keep working across the Reset.
+//
+// The duration d must be greater than accuracy; if not, Reset returns an
+// error and leaves the ticker running with its previous settings. Reset
+// returns an error if the ticker has already been stopped.
+func (t *Ticker) Reset(d, accuracy time.Duration) error {
+	if d <= accuracy {
+		return fmt.Errorf("accuracy %v is not less than duration %v", accuracy, d)
+	}
 
-	return t
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	if t.stopped {
+		return fmt.Errorf("cticker: Reset called on a stopped Ticker")
+	}
+
+	// Retire the current generation's sync/tick goroutines before
+	// replacing the state they read, so they neither race with this
+	// Reset nor leak waiting on a done channel that will never close.
+	close(t.done)
+	if t.ticker != nil {
+		t.ticker.Stop()
+		t.ticker = nil
+	}
+
+	now := t.clock.Now()
+	t.d = d
+	t.a = accuracy
+	t.next = now.Truncate(d).Add(d).Add(t.offset)
+	t.lastNow = now
+	done := make(chan struct{})
+	t.done = done
+
+	go t.sync(now, accuracy, done)
+
+	return nil
 }
 
-// Stop turns off the ticker. After Stop, no more ticks will be sent.
-// Stop does not close the channel to prevent a read from the channel
-// succeeding incorrectly.
+// sync sleeps until the next accuracy boundary and then starts the
+// internal tick loop against a freshly created runtime ticker, unless
+// this generation has been retired by a subsequent Reset or by Stop in
+// the meantime. now and accuracy are a snapshot taken when this
+// generation started, and done is that generation's own done channel,
+// so sync and tick never need to consult, or race with, a newer
+// generation's state.
+func (t *Ticker) sync(now time.Time, accuracy time.Duration, done chan struct{}) {
+	// Synchronise to the accuracy.
+	t.clock.Sleep(now.Truncate(accuracy).Add(accuracy).Sub(now))
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	select {
+	case <-done:
+		// This generation was retired before it could start ticking.
+	default:
+		t.ticker = t.clock.NewTicker(accuracy)
+		go t.tick(t.ticker.Ch(), t.c, done)
+	}
+}
+
+// Stop turns off the ticker. After Stop, no more ticks or jumps will be
+// sent. Stop does not close the channels to prevent a read from either
+// channel succeeding incorrectly.
 func (t *Ticker) Stop() {
+	t.stop(false)
+}
+
+// StopClose turns off the ticker and closes C and Jumps once any tick or
+// jump already in flight has been delivered, so a range over either
+// channel terminates. Prefer Stop unless the caller can guarantee
+// nothing else sends on C or Jumps.
+func (t *Ticker) StopClose() {
+	t.stop(true)
+}
+
+func (t *Ticker) stop(closeC bool) {
 	t.mtx.Lock()
 	defer t.mtx.Unlock()
 
+	if t.stopped {
+		return
+	}
+	t.stopped = true
+	t.closeC = closeC
 	close(t.done)
 	if t.ticker != nil {
 		t.ticker.Stop()
+	} else if closeC {
+		// The sync goroutine hasn't started tick yet, so it never
+		// observed closeC; close here instead.
+		t.closedC.Do(func() {
+			close(t.c)
+			close(t.jumps)
+		})
 	}
 }
 
-// tick sends ticks to t.C with the tickers defined accuracy.
-func (t *Ticker) tick(c chan time.Time) {
-	ticks := t.ticker.Ch()
+// tick sends ticks to t.C with the tickers defined accuracy, and reports
+// jumps to t.Jumps whenever the delta between successive polls of the
+// clock differs from the accuracy by more than the jump threshold. ticks
+// is the channel of the generation's own runtime ticker and done is the
+// generation's own done channel, both captured by sync under the lock
+// when this tick loop was started; done is closed, and only it, when this generation is
+// retired by Reset or Stop, so a newer generation's state is never read
+// or written here without the lock, and this loop never outlives its
+// generation.
+func (t *Ticker) tick(ticks <-chan time.Time, c chan time.Time, done chan struct{}) {
 	for {
 		select {
 		case now := <-ticks:
+			t.mtx.Lock()
+			select {
+			case <-done:
+				// This generation was retired; the tick we just read
+				// belongs to a ticker that's being replaced or torn
+				// down, so drop it without touching the current
+				// generation's state.
+				t.mtx.Unlock()
+				t.finish(c, done)
+				return
+			default:
+			}
+
 			// Remove monotonic clock as we want wall clock comparisons.
 			now = now.Truncate(t.a)
 
+			if delta := now.Sub(t.lastNow); delta-t.a > t.jumpThreshold || t.a-delta > t.jumpThreshold {
+				select {
+				case t.jumps <- delta:
+				default:
+					// Consumer is running slowly
+				}
+			}
+			t.lastNow = now
+
 			// Tick if are within accuracy of the target time or it has past.
 			// This ensures that we tick even if the requested accuracy is
 			// not achievable, for example time.NanoSecond.
+			var send bool
 			if now.Compare(t.next) >= 0 {
 				t.next = t.next.Add(t.d)
+				send = true
+			}
+			t.mtx.Unlock()
+
+			if send {
+				if delay := t.jitterDelay(); delay > 0 {
+					select {
+					case <-time.After(delay):
+					case <-done:
+						t.finish(c, done)
+						return
+					}
+				}
 				select {
 				case c <- now:
 				default:
 					// Consumer is running slowly
 				}
 			}
-		case <-t.done:
+		case <-done:
+			t.finish(c, done)
 			return
 		}
 	}
 }
+
+// finish closes c and t.jumps if this generation's retirement was
+// caused by StopClose rather than by Reset or plain Stop. It is safe to
+// call from any generation: Reset never sets closeC, and closedC
+// ensures the channels are closed at most once even if multiple
+// generations observe a StopClose.
+func (t *Ticker) finish(c chan time.Time, done chan struct{}) {
+	t.mtx.Lock()
+	closeC := t.closeC
+	t.mtx.Unlock()
+	if closeC {
+		t.closedC.Do(func() {
+			close(c)
+			close(t.jumps)
+		})
+	}
+}
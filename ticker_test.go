@@ -1,4 +1,4 @@
-package cticker
+package cticker_test
 
 import (
 	"fmt"
@@ -6,165 +6,213 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/multiplay/go-cticker"
+	"github.com/multiplay/go-cticker/ctickertest"
 )
 
-type mockTicker struct {
-	c      chan time.Time
-	done   chan struct{}
-	period time.Duration
-	times  []time.Time
+// tickerTestSetup returns a fixed start time truncated to both d and a,
+// so expected boundaries are easy to compute.
+func tickerTestSetup() (d, a time.Duration, start time.Time) {
+	d = time.Minute
+	a = time.Second
+	start = time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC)
+	return d, a, start
 }
 
-func (t *mockTicker) run() {
-	tick := time.NewTicker(t.period)
-	defer tick.Stop()
+// newFakeTicker creates a Ticker against clock and waits until it has
+// synchronised to the first accuracy boundary and created its runtime
+// ticker, so the caller can start driving clock.Advance deterministically.
+func newFakeTicker(clock *ctickertest.FakeClock, d, a time.Duration) *cticker.Ticker {
+	tk := cticker.NewWithClock(d, a, clock)
+	clock.BlockUntil(1) // the sync-to-accuracy Sleep has been registered.
+	clock.Advance(a)    // start is accuracy-aligned, so this satisfies it.
+	clock.BlockUntil(1) // the runtime ticker has been created.
+	return tk
+}
 
-	for _, tm := range t.times {
-		select {
-		case <-tick.C:
-			t.c <- tm
-		case <-t.done:
-			return
-		}
+// waitForTick blocks for the next value on c, failing the test if none
+// arrives within a generous real-time timeout.
+func waitForTick(t *testing.T, c <-chan time.Time) time.Time {
+	t.Helper()
+	select {
+	case tick := <-c:
+		return tick
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for tick")
+		return time.Time{}
 	}
 }
 
-func (t *mockTicker) Ch() <-chan time.Time {
-	return t.c
-}
+// TestTickerDrift checks that Ticker ticks on successive wall clock
+// boundaries when the underlying clock advances normally.
+func TestTickerDrift(t *testing.T) {
+	d, a, start := tickerTestSetup()
+
+	clock := ctickertest.NewFakeClock(start)
+	tk := newFakeTicker(clock, d, a)
+	defer tk.Stop()
 
-func (t *mockTicker) Stop() {
-	close(t.done)
+	want := start.Truncate(d).Add(d)
+	for i := 0; i < 3; i++ {
+		for j := 0; j < int(d/a); j++ {
+			clock.Advance(a)
+		}
+		tick := waitForTick(t, tk.C)
+		assert.Equal(t, want, tick)
+		want = want.Add(d)
+	}
 }
 
-func testSetup() (time.Duration, time.Duration, time.Time) {
-	d := time.Minute
-	a := time.Second
-	now := time.Now()
+// TestTickerAdjustment checks that Ticker fires immediately with the
+// adjusted time, rather than waiting out the remaining periods, when
+// the underlying clock observes a large forward jump in a single
+// sample, as happens after an NTP step or a VM suspend/resume.
+func TestTickerAdjustment(t *testing.T) {
+	d, a, start := tickerTestSetup()
 
-	// Ensure we won't trigger the missed tick on the first value.
-	if now.Truncate(a).Add(-a).Equal(now.Truncate(d)) {
-		now = now.Add(a * 2)
-	}
+	clock := ctickertest.NewFakeClock(start)
+	tk := newFakeTicker(clock, d, a)
+	defer tk.Stop()
+
+	before := clock.Now()
+	clock.Advance(time.Hour * 3)
 
-	return d, a, now
+	want := before.Add(time.Hour * 3)
+	tick := waitForTick(t, tk.C)
+	assert.Equal(t, want, tick)
 }
 
-// TestTickerDrift checks that Ticker ticks based on the 'values' returned
-// by the underlying ticker, confirming it will adjust to clock drifts.
-func TestTickerDrift(t *testing.T) {
-	d, a, now := testSetup()
+// TestTickerLostTick checks that Ticker still ticks, one accuracy step
+// after the target, if the underlying clock jumps straight past the
+// sample that would have matched the boundary exactly.
+func TestTickerLostTick(t *testing.T) {
+	d, a, start := tickerTestSetup()
 
-	times := make([]time.Time, 0, 4*d/time.Second)
-	times = addTimes(times, now, now.Add(time.Minute*4), a)
+	clock := ctickertest.NewFakeClock(start)
+	tk := newFakeTicker(clock, d, a)
+	defer tk.Stop()
 
-	ticks := make([]time.Time, 0, 4)
-	ticks = addTicks(ticks, now, now.Add(time.Minute*3), d, a)
+	// Advance to one accuracy step short of the boundary, then jump
+	// two steps at once, skipping the sample that would land exactly
+	// on it.
+	for j := 0; j < int(d/a)-2; j++ {
+		clock.Advance(a)
+	}
+	clock.Advance(a * 2)
 
-	last := testTicker(t, now, d, a, times, ticks)
-	now = time.Now()
-	assert.True(t, now.Before(last), fmt.Sprintln(now, "is not before", last))
+	want := start.Truncate(d).Add(d).Add(a)
+	tick := waitForTick(t, tk.C)
+	assert.Equal(t, want, tick)
 }
 
-// TestTickerAdjustment checks that Ticker deals with clock adjustments.
-func TestTickerAdjustment(t *testing.T) {
-	d, a, now := testSetup()
-	summer := now.Add(-time.Hour)
+// TestTickerJumps checks that a large forward jump in the underlying
+// clock, big enough to exceed the default jump threshold, is reported
+// on Jumps.
+func TestTickerJumps(t *testing.T) {
+	d, a, start := tickerTestSetup()
 
-	times := make([]time.Time, 0, 4*d/a)
-	times = addTimes(times, now, now.Add(time.Minute), a)
-	times = addTimes(times, summer.Add(d*2), summer.Add(d*5), a)
+	clock := ctickertest.NewFakeClock(start)
+	tk := newFakeTicker(clock, d, a)
+	defer tk.Stop()
 
-	ticks := make([]time.Time, 0, 4)
-	ticks = append(ticks, now.Truncate(d).Add(d))
-	ticks = addTicks(ticks, summer.Add(d*2), summer.Add(d*4), d, a)
+	clock.Advance(time.Hour * 3)
 
-	last := testTicker(t, now, d, a, times, ticks)
-	now = time.Now()
-	assert.True(t, now.After(last), fmt.Sprintln(now, "is not after", last))
+	select {
+	case delta := <-tk.Jumps:
+		assert.Greater(t, delta, a)
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for jump")
+	}
 }
 
-// TestTickerLostTick checks that Ticker deals with clock adjustments
-// which result in the underlying tick that we would tick on being missed.
-func TestTickerLostTick(t *testing.T) {
-	d, a, now := testSetup()
+func Test_issue4(t *testing.T) {
+	duration := time.Second
+	ticker := cticker.New(duration, time.Millisecond)
+	timeout := time.After(time.Second * 2)
+	select {
+	case <-ticker.C:
+	case <-timeout:
+		t.Fatal("timeout")
+	}
+}
 
-	times := make([]time.Time, 0, d/a)
-	times = addTimes(times, now, now.Add(time.Minute*4), a)
+// TestTicker_StopClose checks that StopClose delivers exactly the ticks
+// produced before it's called and then closes C, terminating a range
+// over it. It drives the ticker with a FakeClock rather than a real
+// sleep so the tick count is deterministic instead of racing against
+// wall-clock timing.
+func TestTicker_StopClose(t *testing.T) {
+	d, a, start := tickerTestSetup()
 
-	ticks := make([]time.Time, 0, 4)
-	ticks = addTicks(ticks, now, now.Add(time.Minute*3), d, a)
+	clock := ctickertest.NewFakeClock(start)
+	tk := newFakeTicker(clock, d, a)
 
-	// Remove the time which would match the first tick
-	for i, t := range times {
-		if t.Truncate(d) == ticks[0] {
-			times = append(times[:i], times[i+1:]...)
-			ticks[0] = ticks[0].Add(a)
-			break
+	const want = 3
+	ticks := 0
+	for i := 0; i < want; i++ {
+		for j := 0; j < int(d/a); j++ {
+			clock.Advance(a)
 		}
+		waitForTick(t, tk.C)
+		ticks++
 	}
+	tk.StopClose()
 
-	last := testTicker(t, now, d, a, times, ticks)
-	now = time.Now()
-	assert.True(t, now.Before(last), fmt.Sprintln(now, "is not before", last))
+	for range tk.C {
+		ticks++
+	}
+	assert.Equal(t, want, ticks)
 }
 
-// addTimes adds underlying times that we will replay to times and returns the resultant slice.
-func addTimes(times []time.Time, start, end time.Time, a time.Duration) []time.Time {
-	for tick := start; tick.Before(end) || tick.Equal(end); tick = tick.Add(a) {
-		times = append(times, tick)
-	}
+// TestTicker_Reset checks that Reset rejects an invalid period/accuracy
+// pair and that the existing channel keeps delivering ticks afterwards.
+func TestTicker_Reset(t *testing.T) {
+	ticker := cticker.New(time.Second, time.Millisecond)
+	defer ticker.Stop()
 
-	return times
-}
+	err := ticker.Reset(time.Millisecond, time.Millisecond)
+	assert.Error(t, err)
 
-// addTicks adds the expected ticks to ticks and returns the resultant slice.
-func addTicks(ticks []time.Time, start, end time.Time, d, a time.Duration) []time.Time {
-	for tick := start; tick.Before(end) || tick.Equal(end); tick = tick.Add(d) {
-		t := tick.Truncate(d)
-		if t.Equal(tick.Truncate(a)) {
-			// On tick
-			ticks = append(ticks, t)
-		} else {
-			// After the tick
-			ticks = append(ticks, t.Add(d))
-		}
+	require.NoError(t, ticker.Reset(time.Millisecond*20, time.Millisecond))
+
+	timeout := time.After(time.Second * 2)
+	select {
+	case <-ticker.C:
+	case <-timeout:
+		t.Fatal("timeout waiting for tick after Reset")
 	}
-	return ticks
 }
 
-func testTicker(t *testing.T, now time.Time, d, a time.Duration, times, ticks []time.Time) time.Time {
-	newTicker = func(d time.Duration) ticker {
-		t := &mockTicker{
-			c:      make(chan time.Time, 4),
-			done:   make(chan struct{}),
-			period: time.Millisecond,
-			times:  times,
-		}
-		go t.run()
-		return t
-	}
+// TestNewWithOptions_Offset checks that the offset is honored by
+// confirming the delivered tick lands after the unadjusted boundary.
+func TestNewWithOptions_Offset(t *testing.T) {
+	const offset = time.Millisecond * 20
+	before := time.Now().Truncate(time.Millisecond * 50).Add(time.Millisecond * 50)
 
-	tk := New(d, a)
-	defer tk.Stop()
+	ticker := cticker.NewWithOptions(time.Millisecond*50, time.Millisecond, cticker.WithOffset(offset))
+	defer ticker.Stop()
 
-	var i int
-	var tick time.Time
-	for {
-		tick = <-tk.C
-		assert.Equal(t, ticks[i], tick)
-		i++
-		if i == len(ticks) {
-			break
-		}
+	timeout := time.After(time.Second * 2)
+	select {
+	case tick := <-ticker.C:
+		assert.True(t, tick.After(before), fmt.Sprintln(tick, "is not after", before))
+	case <-timeout:
+		t.Fatal("timeout")
 	}
-
-	return tick
 }
 
-func Test_issue4(t *testing.T) {
-	duration := time.Second
-	ticker := New(duration, time.Millisecond)
+// TestNewWithOptions_Jitter checks that a jittered ticker still delivers
+// a tick.
+func TestNewWithOptions_Jitter(t *testing.T) {
+	const d = time.Millisecond * 50
+	const a = time.Millisecond
+	const jitter = time.Millisecond * 20
+
+	ticker := cticker.NewWithOptions(d, a, cticker.WithJitter(jitter))
+	defer ticker.Stop()
+
 	timeout := time.After(time.Second * 2)
 	select {
 	case <-ticker.C:
@@ -173,16 +221,35 @@ func Test_issue4(t *testing.T) {
 	}
 }
 
-func TestTicker_StopClose(t *testing.T) {
-	ticker := New(time.Millisecond, time.Nanosecond)
-	go func() {
-		time.Sleep(time.Millisecond * 10)
-		ticker.StopClose()
-	}()
+// TestNewWithOptions_JumpThreshold checks that a ticker configured with
+// a custom jump threshold still ticks normally.
+func TestNewWithOptions_JumpThreshold(t *testing.T) {
+	const d = time.Millisecond * 50
+	const a = time.Millisecond
 
-	ticks := 0
-	for range ticker.C {
-		ticks++
+	ticker := cticker.NewWithOptions(d, a, cticker.WithJumpThreshold(time.Millisecond*10))
+	defer ticker.Stop()
+
+	timeout := time.After(time.Second * 2)
+	select {
+	case <-ticker.C:
+	case <-timeout:
+		t.Fatal("timeout")
 	}
-	assert.GreaterOrEqual(t, 9, ticks)
+}
+
+// TestNewWithOptions_InvalidOffset checks NewWithOptions panics when
+// offset is not less than d.
+func TestNewWithOptions_InvalidOffset(t *testing.T) {
+	assert.Panics(t, func() {
+		cticker.NewWithOptions(time.Second, time.Millisecond, cticker.WithOffset(time.Second))
+	})
+}
+
+// TestNewWithOptions_InvalidJitter checks NewWithOptions panics when
+// jitter is greater than d-accuracy.
+func TestNewWithOptions_InvalidJitter(t *testing.T) {
+	assert.Panics(t, func() {
+		cticker.NewWithOptions(time.Second, time.Millisecond, cticker.WithJitter(time.Second))
+	})
 }